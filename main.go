@@ -3,10 +3,15 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"os/exec"
+	"regexp"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 	"unicode"
@@ -33,10 +38,27 @@ const (
 
 type App struct {
 	cfg       config
-	cache     string
 	suspend   bool
 	highlight int
 
+	// mu guards cmdArgs, cache, snapshots and viewIdx, which the UI
+	// goroutine (submitCommand, viewSnapshot, writeSnapshot) and the tick
+	// goroutine (tick/runUntilRestart/exec) both read and write
+	// concurrently.
+	mu        sync.Mutex
+	cmdArgs   []string
+	cache     string
+	snapshots []snapshot
+	viewIdx   int
+
+	history []string
+	restart chan struct{}
+
+	alertRe *regexp.Regexp
+	iter    int
+
+	tokens tokenCache
+
 	ui       *tview.Application
 	title    *tview.TextView
 	status   *tview.TextView
@@ -44,21 +66,43 @@ type App struct {
 	footer   *tview.TextView
 	content  *tview.TextView
 	display  *tview.Flex
+	prompt   *tview.InputField
+}
+
+// snapshot records a single command run so it can be revisited with the
+// [ and ] history-navigation keybindings.
+type snapshot struct {
+	text     string
+	time     time.Time
+	exitCode int
 }
 
 type config struct {
-	ErrExit       bool    `short:"e" long:"errexit"  description:"Exit if command has a non-zero exit"`
-	Interval      float64 `short:"n" long:"interval" description:"Time in seconds to wait between updates" default:"2.0"`
-	NoTitle       bool    `short:"t" long:"no-title" description:"Turn off header"`
-	Exec          bool    `short:"x" long:"exec"     description:"Pass command to exec instead of \"sh -c\""`
-	HighlightMode string  `short:"m" long:"mode"     description:"Highlight mode" choice:"none" choice:"char" choice:"word" choice:"line" default:"none"`
-	ColorStyle    string  `short:"s" long:"style"    description:"Interpret color and style sequences"`
-	Version       func()  `short:"v" long:"version"  description:"Output version information and exit"`
+	ErrExit          bool     `short:"e" long:"errexit"  description:"Exit if command has a non-zero exit"`
+	Interval         float64  `short:"n" long:"interval" description:"Time in seconds to wait between updates" default:"2.0"`
+	NoTitle          bool     `short:"t" long:"no-title" description:"Turn off header"`
+	Exec             bool     `short:"x" long:"exec"     description:"Pass command to exec instead of \"sh -c\""`
+	Ansi             bool     `short:"a" long:"ansi"     description:"Interpret ANSI color and style escape sequences in the command output"`
+	HighlightMode    string   `short:"m" long:"mode"     description:"Highlight mode" choice:"none" choice:"char" choice:"word" choice:"line" default:"none"`
+	ColorStyle       string   `short:"s" long:"style"            description:"Interpret color and style sequences"`
+	DiffAddedStyle   string   `long:"diff-added-style"   description:"Style tag for inserted tokens in the diff" default:"green"`
+	DiffRemovedStyle string   `long:"diff-removed-style" description:"Style tag for removed tokens in the diff" default:"red::s"`
+	HistorySize      int      `long:"history"            description:"Number of past outputs to retain for history navigation ([ and ])" default:"20"`
+	JSON             bool     `long:"json"               description:"Write one NDJSON event per invocation to stdout instead of running the interactive UI"`
+	AlertMatch       string   `long:"alert-match"        description:"Ring an alert when the output matches this regular expression"`
+	AlertChange      bool     `long:"alert-change"       description:"Ring an alert when the output changes from the previous run"`
+	AlertNonZero     bool     `long:"alert-nonzero"      description:"Ring an alert when the command exits with a non-zero status"`
+	OnAlert          string   `long:"on-alert"           description:"Command to run on alert; GWATCH_MATCH, GWATCH_EXIT and GWATCH_ITER are set in its environment and the output is piped to its stdin"`
+	Commands         []string `short:"C" long:"command" description:"Run a named command as a dashboard pane: NAME:CMD (repeatable); the positional command is not run when any is given"`
+	Layout           string   `long:"layout"             description:"Dashboard pane layout" choice:"grid" choice:"rows" choice:"cols" default:"grid"`
+	Version          func()   `short:"v" long:"version"  description:"Output version information and exit"`
 }
 
 func NewApp(cfg config) *App {
 	a := &App{
 		cfg:      cfg,
+		restart:  make(chan struct{}, 1),
+		viewIdx:  -1,
 		ui:       tview.NewApplication(),
 		title:    tview.NewTextView(),
 		datetime: tview.NewTextView(),
@@ -89,9 +133,17 @@ func NewApp(cfg config) *App {
 			a.setHighlightMode((a.highlight + 1) % numHighlightMode)
 		case 'p':
 			a.setSuspendMode(!a.suspend)
+		case ':':
+			a.showCommandPrompt()
+		case '[':
+			a.viewSnapshot(a.viewIndex() - 1)
+		case ']':
+			a.viewSnapshot(a.viewIndex() + 1)
+		case 'w':
+			a.writeSnapshot()
 		case '?':
 			if a.footer == nil {
-				a.showMessage("[j]Down [k]Up [h]Left [l]Right [g]Top [G]Bottom [d]Highlight [p]Pause [?]Help [q]Quit")
+				a.showMessage("[j]Down [k]Up [h]Left [l]Right [g]Top [G]Bottom [d]Highlight [p]Pause [[/]]History [w]Write [:]Edit command [?]Help [q]Quit")
 				a.ui.SetFocus(a.footer)
 				a.footer.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 					a.hideMessage()
@@ -117,6 +169,15 @@ func NewApp(cfg config) *App {
 		a.setHighlightMode(HighlightModeOff)
 	}
 
+	if a.cfg.AlertMatch != "" {
+		re, err := regexp.Compile(a.cfg.AlertMatch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --alert-match pattern: %s\n", err)
+			os.Exit(1)
+		}
+		a.alertRe = re
+	}
+
 	a.ui.SetRoot(a.display, true)
 	return a
 }
@@ -127,6 +188,10 @@ func (a *App) Start(args []string) {
 }
 
 func (a *App) showMessage(message string) {
+	if a.footer != nil {
+		a.hideMessage()
+	}
+
 	a.footer = tview.NewTextView()
 	a.footer.SetText(message)
 	a.display.AddItem(a.footer, 1, 0, false)
@@ -137,6 +202,98 @@ func (a *App) hideMessage() {
 	a.footer = nil
 }
 
+// showCommandPrompt opens a liner-style input line at the bottom of the
+// display, seeded with the command currently running, so it can be edited
+// and re-run without restarting gwatch. Up/Down recall previous commands
+// submitted earlier in this session.
+func (a *App) showCommandPrompt() {
+	if a.prompt != nil {
+		return
+	}
+
+	histIdx := len(a.history)
+
+	a.prompt = tview.NewInputField()
+	a.prompt.SetLabel(": ")
+	a.prompt.SetText(strings.Join(a.command(), " "))
+	a.prompt.SetFieldWidth(0)
+	a.prompt.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			a.submitCommand(a.prompt.GetText())
+		case tcell.KeyEscape:
+			a.hideCommandPrompt()
+		}
+	})
+	a.prompt.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyUp:
+			if histIdx > 0 {
+				histIdx--
+				a.prompt.SetText(a.history[histIdx])
+			}
+			return nil
+		case tcell.KeyDown:
+			if histIdx < len(a.history) {
+				histIdx++
+			}
+			if histIdx == len(a.history) {
+				a.prompt.SetText("")
+			} else {
+				a.prompt.SetText(a.history[histIdx])
+			}
+			return nil
+		}
+		return event
+	})
+
+	a.display.AddItem(a.prompt, 1, 0, false)
+	a.ui.SetFocus(a.prompt)
+}
+
+func (a *App) hideCommandPrompt() {
+	a.display.RemoveItem(a.prompt)
+	a.prompt = nil
+	a.ui.SetFocus(a.content)
+}
+
+// submitCommand records the edited command line in history and asks the
+// tick loop to restart against it.
+func (a *App) submitCommand(line string) {
+	a.hideCommandPrompt()
+
+	line = strings.TrimSpace(line)
+	if line == "" || line == strings.Join(a.command(), " ") {
+		return
+	}
+
+	a.history = append(a.history, line)
+	a.setCommand(strings.Fields(line))
+
+	select {
+	case a.restart <- struct{}{}:
+	default:
+	}
+}
+
+// command returns the command line currently being watched. It is safe to
+// call from either the UI goroutine or the tick goroutine.
+func (a *App) command() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.cmdArgs
+}
+
+// setCommand replaces the command line being watched and clears the diff
+// cache so the next render starts fresh instead of diffing against output
+// from the previous command.
+func (a *App) setCommand(cmdArgs []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cmdArgs = cmdArgs
+	a.cache = ""
+}
+
 func (a *App) setHighlightMode(mode int) {
 	a.highlight = mode
 	switch a.highlight {
@@ -158,47 +315,456 @@ func (a *App) setSuspendMode(mode bool) {
 	if a.suspend {
 		a.showMessage("Command execution is paused, press [p] to resume")
 	} else {
+		a.setViewIndex(-1)
 		a.hideMessage()
 		a.datetime.SetText(time.Now().Format(time.ANSIC))
+		a.setHighlightMode(a.highlight) // restore the status bar after viewing history
 	}
 }
 
+// recordSnapshot appends the latest run to the bounded ring buffer used for
+// history navigation, evicting the oldest entry once cfg.HistorySize is
+// exceeded.
+func (a *App) recordSnapshot(text string, at time.Time, exitCode int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.snapshots = append(a.snapshots, snapshot{text: text, time: at, exitCode: exitCode})
+	if over := len(a.snapshots) - a.cfg.HistorySize; over > 0 {
+		a.snapshots = a.snapshots[over:]
+	}
+}
+
+// lastSnapshotChanged reports whether text differs from the most recently
+// recorded snapshot, or false if there isn't one yet.
+func (a *App) lastSnapshotChanged(text string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.snapshots) > 0 && a.snapshots[len(a.snapshots)-1].text != text
+}
+
+// viewIndex returns the history cursor (-1 when following live output). It
+// is safe to call from either the UI goroutine or the tick goroutine.
+func (a *App) viewIndex() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.viewIdx
+}
+
+func (a *App) setViewIndex(idx int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.viewIdx = idx
+}
+
+// viewSnapshot moves the history cursor to idx (clamped to the available
+// range), pauses live updates, and renders the snapshot at idx diffed
+// against its predecessor instead of against the newest output.
+func (a *App) viewSnapshot(idx int) {
+	a.mu.Lock()
+	if len(a.snapshots) == 0 {
+		a.mu.Unlock()
+		return
+	}
+
+	if idx < 0 {
+		idx = 0
+	}
+	if last := len(a.snapshots) - 1; idx > last {
+		idx = last
+	}
+
+	a.viewIdx = idx
+	if idx > 0 {
+		a.cache = a.snapshots[idx-1].text
+	} else {
+		a.cache = ""
+	}
+	s := a.snapshots[idx]
+	total := len(a.snapshots)
+	a.mu.Unlock()
+
+	a.suspend = true
+	a.showMessage("Command execution is paused, press [p] to resume")
+
+	a.content.SetText(a.highlightContent(s.text))
+	a.status.SetText(fmt.Sprintf("snapshot %d/%d @ %s exit=%d", idx+1, total, s.time.Format("15:04:05"), s.exitCode))
+}
+
+// writeSnapshot writes the captured output of the currently viewed snapshot
+// (or the most recent run, if not browsing history) to a timestamped file in
+// the working directory. Under --ansi that output has already been
+// translated into tview color tags, so the escape sequences are not written.
+func (a *App) writeSnapshot() {
+	a.mu.Lock()
+	if len(a.snapshots) == 0 {
+		a.mu.Unlock()
+		return
+	}
+
+	idx := a.viewIdx
+	if idx < 0 {
+		idx = len(a.snapshots) - 1
+	}
+	s := a.snapshots[idx]
+	a.mu.Unlock()
+
+	name := fmt.Sprintf("gwatch-%s.txt", s.time.Format("20060102-150405"))
+	if err := os.WriteFile(name, []byte(s.text), 0644); err != nil {
+		a.showMessage(fmt.Sprintf("Failed to write snapshot: %s", err.Error()))
+		return
+	}
+
+	a.showMessage(fmt.Sprintf("Snapshot written to %s", name))
+}
+
+// checkAlert rings the terminal bell, flashes the status bar, and spawns
+// --on-alert whenever the output matches --alert-match, changed from the
+// previous run under --alert-change, or the command exited non-zero under
+// --alert-nonzero.
+func (a *App) checkAlert(text string, exitCode int, changed bool) {
+	a.iter++
+
+	var match string
+	if a.alertRe != nil {
+		match = a.alertRe.FindString(text)
+	}
+
+	triggered := match != "" || (a.cfg.AlertChange && changed) || (a.cfg.AlertNonZero && exitCode != 0)
+	if !triggered {
+		return
+	}
+
+	fmt.Fprint(os.Stdout, "\a")
+	a.flashStatus(fmt.Sprintf("ALERT iter=%d exit=%d", a.iter, exitCode))
+	a.runOnAlert(text, match, exitCode)
+}
+
+// flashStatus briefly shows message in the status bar in red on white
+// before restoring the highlight-mode text it normally carries.
+func (a *App) flashStatus(message string) {
+	mode := a.highlight
+	a.status.SetText(fmt.Sprintf("[red:white]%s[-:-:-]", message))
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		a.setHighlightMode(mode)
+	}()
+}
+
+// runOnAlert pipes text to the stdin of --on-alert, with GWATCH_MATCH,
+// GWATCH_EXIT and GWATCH_ITER set in its environment.
+func (a *App) runOnAlert(text, match string, exitCode int) {
+	if a.cfg.OnAlert == "" {
+		return
+	}
+
+	c := exec.Command("sh", "-c", a.cfg.OnAlert)
+	c.Stdin = strings.NewReader(text)
+	c.Env = append(os.Environ(),
+		fmt.Sprintf("GWATCH_MATCH=%s", match),
+		fmt.Sprintf("GWATCH_EXIT=%d", exitCode),
+		fmt.Sprintf("GWATCH_ITER=%d", a.iter),
+	)
+
+	go c.Run()
+}
+
 func (a *App) highlightContent(text string) string {
-	if a.highlight == HighlightModeOff || a.cache == "" {
-		a.cache = text
-		return tview.Escape(text)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return renderHighlight(&a.cache, &a.tokens, a.highlight, a.style(), text)
+}
+
+func (a *App) style() highlightStyle {
+	return highlightStyle{
+		changed: a.cfg.ColorStyle,
+		added:   a.cfg.DiffAddedStyle,
+		removed: a.cfg.DiffRemovedStyle,
+		ansi:    a.cfg.Ansi,
+	}
+}
+
+// highlightStyle groups the tag styles renderHighlight applies: the
+// --style used by the size-capped fallback, and the --diff-added-style /
+// --diff-removed-style used by the LCS-based diff.
+type highlightStyle struct {
+	changed string
+	added   string
+	removed string
+	ansi    bool
+}
+
+// diffSizeCap bounds the LCS diff to keep interactive latency bounded;
+// above this many combined tokens, renderHighlight falls back to the
+// cheaper positional comparison.
+const diffSizeCap = 50000
+
+// diffEditCap bounds the edit distance d the Myers algorithm is allowed to
+// search out to. diffTokens is O(d*(n+m)) in both time and space because it
+// keeps a full snapshot of v for every d, so a large d is just as costly as
+// a large token count even when n+m stays under diffSizeCap (e.g. two
+// almost entirely dissimilar outputs). Once d exceeds this, diffTokens
+// aborts and renderHighlight falls back to the positional comparison.
+const diffEditCap = 1000
+
+// tokenCache memoizes the token slice of the previous render, keyed by the
+// exact text and highlight mode it was computed from, so unchanged history
+// isn't re-scanned with bufio.Scanner on every tick.
+type tokenCache struct {
+	text   string
+	mode   int
+	tokens []string
+}
+
+func (c *tokenCache) get(text string, mode int) []string {
+	if c.text == text && c.mode == mode {
+		return c.tokens
 	}
+	return tokenize(text, mode)
+}
 
+func (c *tokenCache) put(text string, mode int, tokens []string) {
+	c.text, c.mode, c.tokens = text, mode, tokens
+}
+
+// renderHighlight diffs text against cache under the given highlight mode,
+// returning the tview markup to display, and leaves cache (and its token
+// cache) updated to text. It is shared by the single-command App and each
+// dashboard Pane so both highlight the same way.
+//
+// Tokens are compared with a Myers/LCS edit script rather than index by
+// index, so a single insertion or deletion doesn't cascade into every
+// following token being marked changed.
+func renderHighlight(cache *string, tc *tokenCache, highlight int, style highlightStyle, text string) string {
+	// In ANSI mode the text has already been translated into tview's own
+	// color/region tags, so it must be passed through untouched instead of
+	// being escaped a second time.
+	escape := tview.Escape
+	if style.ansi {
+		escape = func(s string) string { return s }
+	}
+
+	// Char/word tokens can land in the middle of a tag ansiWriter already
+	// translated the text into (e.g. "[red]foo[-]"), and wrapping such a
+	// fragment in another tag corrupts the markup. A tag never spans
+	// multiple lines, so fall back to line-mode diffing instead.
+	mode := highlight
+	if style.ansi && (mode == HighlightModeChar || mode == HighlightModeWord) {
+		mode = HighlightModeLine
+	}
+
+	if mode == HighlightModeOff || *cache == "" {
+		*cache = text
+		tc.put(text, mode, tokenize(text, mode))
+		return escape(text)
+	}
+
+	oldTokens := tc.get(*cache, mode)
+	newTokens := tokenize(text, mode)
+
+	var buf bytes.Buffer
+	ops, ok := []diffOp(nil), false
+	if len(oldTokens)+len(newTokens) <= diffSizeCap {
+		ops, ok = diffTokens(oldTokens, newTokens)
+	}
+	if !ok {
+		buf.WriteString(renderPositional(oldTokens, newTokens, style.changed, escape))
+	} else {
+		for _, op := range ops {
+			switch op.kind {
+			case diffEqual:
+				buf.WriteString(escape(op.token))
+			case diffInsert:
+				fmt.Fprintf(&buf, "[%s]%s[-:-:-]", style.added, escape(op.token))
+			case diffDelete:
+				fmt.Fprintf(&buf, "[%s]%s[-:-:-]", style.removed, escape(op.token))
+			}
+		}
+	}
+
+	*cache = text
+	tc.put(text, mode, newTokens)
+	return buf.String()
+}
+
+// tokenize splits text into the unit highlight operates on: runes, words,
+// or lines.
+func tokenize(text string, highlight int) []string {
 	var split bufio.SplitFunc
-	switch a.highlight {
+	switch highlight {
 	case HighlightModeChar:
 		split = scanRunes
 	case HighlightModeWord:
 		split = scanWords
 	case HighlightModeLine:
 		split = scanLines
+	default:
+		return nil
 	}
 
-	t1 := bufio.NewScanner(strings.NewReader(text))
-	t1.Split(split)
+	s := bufio.NewScanner(strings.NewReader(text))
+	s.Split(split)
 
-	t2 := bufio.NewScanner(strings.NewReader(a.cache))
-	t2.Split(split)
+	var tokens []string
+	for s.Scan() {
+		tokens = append(tokens, s.Text())
+	}
 
+	return tokens
+}
+
+// renderPositional is the original index-by-index comparison, kept as the
+// fast path for diffs too large to run the LCS algorithm on interactively.
+func renderPositional(oldTokens, newTokens []string, colorStyle string, escape func(string) string) string {
 	var buf bytes.Buffer
-	for t1.Scan() {
-		token := t1.Text()
-		if t2.Scan() && token == t2.Text() {
-			fmt.Fprintf(&buf, "%s", tview.Escape(token))
+	for i, token := range newTokens {
+		if i < len(oldTokens) && token == oldTokens[i] {
+			buf.WriteString(escape(token))
 		} else {
-			fmt.Fprintf(&buf, "[%s]%s[-:-:-]", a.cfg.ColorStyle, tview.Escape(token))
+			fmt.Fprintf(&buf, "[%s]%s[-:-:-]", colorStyle, escape(token))
 		}
 	}
 
-	a.cache = text
 	return buf.String()
 }
 
+type diffOpKind int
+
+const (
+	diffEqual diffOpKind = iota
+	diffInsert
+	diffDelete
+)
+
+type diffOp struct {
+	kind  diffOpKind
+	token string
+}
+
+// diffTokens computes an LCS-based edit script between old and cur using
+// Myers' O(ND) algorithm, producing Equal/Insert/Delete operations instead
+// of the positional comparison's cascade of false positives.
+//
+// The algorithm is O(d*(n+m)) in both time and space, where d is the edit
+// distance, because it keeps a full snapshot of v for every d it searches.
+// d can approach n+m when old and cur share little content (e.g. watching
+// ps or a log tail), so the search is capped at diffEditCap; if it's
+// exceeded diffTokens gives up and returns ok=false rather than continuing
+// to allocate snapshots, leaving the caller to fall back to a cheaper diff.
+func diffTokens(old, cur []string) (ops []diffOp, ok bool) {
+	n, m := len(old), len(cur)
+	max := n + m
+	if max == 0 {
+		return nil, true
+	}
+
+	editCap := max
+	if editCap > diffEditCap {
+		editCap = diffEditCap
+	}
+
+	offset := max
+	size := 2*max + 1
+	trace := make([][]int, 0, editCap+1)
+
+	v := make([]int, size)
+	dFound := -1
+
+diffLoop:
+	for d := 0; d <= editCap; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+
+			for x < n && y < m && old[x] == cur[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				dFound = d
+				snapshot := make([]int, size)
+				copy(snapshot, v)
+				trace = append(trace, snapshot)
+				break diffLoop
+			}
+		}
+
+		snapshot := make([]int, size)
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+	}
+
+	if dFound < 0 {
+		return nil, false
+	}
+
+	x, y := n, m
+	for d := dFound; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{kind: diffEqual, token: old[x-1]})
+			x--
+			y--
+		}
+
+		if x == prevX {
+			ops = append(ops, diffOp{kind: diffInsert, token: cur[y-1]})
+			y--
+		} else {
+			ops = append(ops, diffOp{kind: diffDelete, token: old[x-1]})
+			x--
+		}
+	}
+
+	for x > 0 && y > 0 {
+		ops = append(ops, diffOp{kind: diffEqual, token: old[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops, true
+}
+
+// ansiWriter wraps buf so that command output is translated from SGR escape
+// sequences into tview color tags as it is written, instead of being
+// captured as literal escape codes.
+func (a *App) ansiWriter(buf *bytes.Buffer) io.Writer {
+	return newANSIWriter(buf, a.cfg.Ansi)
+}
+
+func newANSIWriter(buf *bytes.Buffer, ansi bool) io.Writer {
+	if !ansi {
+		return buf
+	}
+
+	return tview.ANSIWriter(buf)
+}
+
 func (a *App) exec(cmdArgs []string) int {
 	var c *exec.Cmd
 	if a.cfg.Exec {
@@ -208,53 +774,78 @@ func (a *App) exec(cmdArgs []string) int {
 	}
 
 	var buf bytes.Buffer
-	c.Stdout = &buf
-	c.Stderr = &buf
+	w := a.ansiWriter(&buf)
+	c.Stdout = w
+	c.Stderr = w
 	err := c.Run()
 
-	a.datetime.SetText(time.Now().Format(time.ANSIC))
-	a.content.SetText(a.highlightContent(buf.String()))
-
+	exitCode := 0
 	if err != nil {
-		switch e := err.(type) {
-		case *exec.ExitError:
+		exitCode = 1
+		if e, ok := err.(*exec.ExitError); ok {
 			if status, ok := e.Sys().(syscall.WaitStatus); ok {
-				return status.ExitStatus()
+				exitCode = status.ExitStatus()
 			}
+		} else {
+			fmt.Fprintln(&buf, err.Error())
 		}
+	}
+
+	text := buf.String()
+	changed := a.lastSnapshotChanged(text)
 
-		fmt.Fprintln(a.content, err.Error())
-		return 1
+	now := time.Now()
+	a.datetime.SetText(now.Format(time.ANSIC))
+	a.recordSnapshot(text, now, exitCode)
+	a.checkAlert(text, exitCode, changed)
+
+	if a.viewIndex() < 0 {
+		a.content.SetText(a.highlightContent(text))
 	}
 
-	return 0
+	return exitCode
 }
 
 func (a *App) tick(cmdArgs []string) {
+	a.setCommand(cmdArgs)
+
+	for a.runUntilRestart() {
+	}
+}
+
+// runUntilRestart drives the tick loop for the command currently stored in
+// a.cmdArgs. It returns true if the command was replaced through the
+// command prompt and the loop should restart against it, or false if the
+// command exited non-zero under --errexit and the application should stop.
+func (a *App) runUntilRestart() bool {
 	t := time.NewTicker(time.Duration(a.cfg.Interval*1000) * time.Millisecond)
 	defer t.Stop()
 
+	cmdArgs := a.command()
 	a.title.SetText(fmt.Sprintf("Every %.1fs: %s", a.cfg.Interval, strings.Join(cmdArgs, " ")))
 	errCode := a.exec(cmdArgs)
 
 	for {
 		if errCode != 0 && a.cfg.ErrExit {
-			break
+			a.showMessage("Command exit with a non-zero status, press a key to exit")
+			a.ui.SetFocus(a.footer)
+			a.footer.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+				a.ui.Stop()
+				os.Exit(errCode)
+				return event
+			})
+			return false
 		}
 
-		<-t.C
-		if !a.suspend {
-			errCode = a.exec(cmdArgs)
+		select {
+		case <-a.restart:
+			return true
+		case <-t.C:
+			if !a.suspend {
+				errCode = a.exec(cmdArgs)
+			}
 		}
 	}
-
-	a.showMessage("Command exit with a non-zero status, press a key to exit")
-	a.ui.SetFocus(a.footer)
-	a.footer.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		a.ui.Stop()
-		os.Exit(errCode)
-		return event
-	})
 }
 
 func scanRunes(data []byte, atEOF bool) (int, []byte, error) {
@@ -308,6 +899,473 @@ func scanLines(data []byte, atEOF bool) (int, []byte, error) {
 	return 0, nil, nil
 }
 
+// jsonEvent is the NDJSON schema written to stdout once per invocation in
+// --json mode.
+type jsonEvent struct {
+	Timestamp    time.Time     `json:"timestamp"`
+	ExitCode     int           `json:"exit_code"`
+	DurationMs   int64         `json:"duration_ms"`
+	Stdout       string        `json:"stdout"`
+	Stderr       string        `json:"stderr"`
+	ChangedBytes int           `json:"changed_bytes"`
+	ChangedLines []changedLine `json:"changed_lines,omitempty"`
+}
+
+// changedLine describes a single line position that differs between the
+// previous and current invocation when the command is run in line mode.
+type changedLine struct {
+	Lineno int    `json:"lineno"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+}
+
+// jsonRunner drives the command on the same interval as the TUI's App, but
+// writes an NDJSON event per run instead of drawing a screen.
+type jsonRunner struct {
+	cfg   config
+	cache string
+}
+
+func runJSON(cfg config, cmdArgs []string) {
+	r := &jsonRunner{cfg: cfg}
+	enc := json.NewEncoder(os.Stdout)
+
+	t := time.NewTicker(time.Duration(cfg.Interval*1000) * time.Millisecond)
+	defer t.Stop()
+
+	errCode := r.exec(cmdArgs, enc)
+	for {
+		if errCode != 0 && cfg.ErrExit {
+			os.Exit(errCode)
+		}
+
+		<-t.C
+		errCode = r.exec(cmdArgs, enc)
+	}
+}
+
+func (r *jsonRunner) exec(cmdArgs []string, enc *json.Encoder) int {
+	var c *exec.Cmd
+	if r.cfg.Exec {
+		c = exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	} else {
+		c = exec.Command("sh", "-c", strings.Join(cmdArgs, " "))
+	}
+
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	start := time.Now()
+	err := c.Run()
+	duration := time.Since(start)
+
+	exitCode := 0
+	if err != nil {
+		exitCode = 1
+		if e, ok := err.(*exec.ExitError); ok {
+			if status, ok := e.Sys().(syscall.WaitStatus); ok {
+				exitCode = status.ExitStatus()
+			}
+		} else {
+			fmt.Fprintln(&stderr, err.Error())
+		}
+	}
+
+	text := stdout.String()
+	changedBytes, changedLines := r.diff(text)
+
+	enc.Encode(&jsonEvent{
+		Timestamp:    start,
+		ExitCode:     exitCode,
+		DurationMs:   duration.Milliseconds(),
+		Stdout:       text,
+		Stderr:       stderr.String(),
+		ChangedBytes: changedBytes,
+		ChangedLines: changedLines,
+	})
+
+	return exitCode
+}
+
+// diff compares text against the previous invocation's output using the
+// same tokenize/diffTokens LCS edit script that powers highlightContent, so
+// --json reports the same notion of "changed" the TUI highlights: a single
+// inserted or deleted line doesn't cascade into every following line being
+// reported as changed.
+func (r *jsonRunner) diff(text string) (int, []changedLine) {
+	prev := r.cache
+	r.cache = text
+
+	var lines []changedLine
+	if r.cfg.HighlightMode == "line" && prev != "" {
+		lines = diffLines(prev, text)
+	}
+
+	return diffTokenCount(prev, text), lines
+}
+
+// diffTokenCount counts the bytes belonging to runes inserted into or
+// deleted from cur relative to old, per the LCS edit script, so output that
+// only shrinks still reports a nonzero change. Falls back to the cheaper
+// positional comparison above diffSizeCap or once the edit distance exceeds
+// diffEditCap (see renderHighlight).
+func diffTokenCount(old, cur string) int {
+	if old == "" {
+		return 0
+	}
+
+	oldTokens := tokenize(old, HighlightModeChar)
+	curTokens := tokenize(cur, HighlightModeChar)
+
+	// scanRunes escapes "]" as "[]" for tview; count it as the one byte it is.
+	for _, tokens := range [][]string{oldTokens, curTokens} {
+		for i, token := range tokens {
+			if token == "[]" {
+				tokens[i] = "]"
+			}
+		}
+	}
+
+	ops, ok := []diffOp(nil), false
+	if len(oldTokens)+len(curTokens) <= diffSizeCap {
+		ops, ok = diffTokens(oldTokens, curTokens)
+	}
+	if !ok {
+		return positionalChangedCount(oldTokens, curTokens)
+	}
+
+	n := 0
+	for _, op := range ops {
+		if op.kind == diffInsert || op.kind == diffDelete {
+			n += len(op.token)
+		}
+	}
+
+	return n
+}
+
+// positionalChangedCount is diffTokenCount's fallback for diffs too large
+// to run the LCS algorithm on: it pairs tokens by index, same as
+// renderPositional.
+func positionalChangedCount(oldTokens, curTokens []string) int {
+	n := 0
+	for i, token := range curTokens {
+		if i >= len(oldTokens) || token != oldTokens[i] {
+			n += len(token)
+		}
+	}
+	for i := len(curTokens); i < len(oldTokens); i++ {
+		n += len(oldTokens[i])
+	}
+
+	return n
+}
+
+// diffLines reports every line inserted, deleted, or changed between old
+// and cur, for consumers that requested line-mode highlighting. It's built
+// on the same LCS edit script as highlightContent, so one inserted or
+// deleted line is reported once instead of shifting every following line
+// out of alignment.
+func diffLines(old, cur string) []changedLine {
+	oldTokens := tokenize(old, HighlightModeLine)
+	curTokens := tokenize(cur, HighlightModeLine)
+
+	ops, ok := []diffOp(nil), false
+	if len(oldTokens)+len(curTokens) <= diffSizeCap {
+		ops, ok = diffTokens(oldTokens, curTokens)
+	}
+	if !ok {
+		return positionalChangedLines(oldTokens, curTokens)
+	}
+
+	var lines []changedLine
+	oldLineno, curLineno := 1, 1
+	for _, op := range ops {
+		switch op.kind {
+		case diffEqual:
+			oldLineno++
+			curLineno++
+		case diffInsert:
+			lines = append(lines, changedLine{Lineno: curLineno, New: op.token})
+			curLineno++
+		case diffDelete:
+			lines = append(lines, changedLine{Lineno: oldLineno, Old: op.token})
+			oldLineno++
+		}
+	}
+
+	return lines
+}
+
+// positionalChangedLines is diffLines' fallback for diffs too large to run
+// the LCS algorithm on: it pairs lines by index, same as renderPositional.
+func positionalChangedLines(oldTokens, curTokens []string) []changedLine {
+	var lines []changedLine
+	for i, curLine := range curTokens {
+		var oldLine string
+		hasOld := i < len(oldTokens)
+		if hasOld {
+			oldLine = oldTokens[i]
+		}
+
+		if !hasOld || curLine != oldLine {
+			lines = append(lines, changedLine{Lineno: i + 1, Old: oldLine, New: curLine})
+		}
+	}
+
+	return lines
+}
+
+// Pane is one tiled command view in dashboard mode, driven by its own tick
+// goroutine and highlight cache. It mirrors the single-command App's
+// exec/highlight loop, scoped to one bordered TextView.
+type Pane struct {
+	name    string
+	cmdArgs []string
+	cfg     config
+
+	// mu guards cache, tokens, highlight and suspend, which the dashboard's
+	// UI goroutine (the pane's key handler in runDashboard) and this
+	// pane's own tick goroutine (tick/exec) both read and write
+	// concurrently.
+	mu        sync.Mutex
+	cache     string
+	tokens    tokenCache
+	highlight int
+	suspend   bool
+
+	content *tview.TextView
+}
+
+func NewPane(name string, cmdArgs []string, cfg config) *Pane {
+	p := &Pane{name: name, cmdArgs: cmdArgs, cfg: cfg}
+
+	p.content = tview.NewTextView()
+	p.content.SetDynamicColors(true)
+	p.content.SetBorder(true)
+	p.content.SetTitle(name)
+
+	switch cfg.HighlightMode {
+	case "char":
+		p.highlight = HighlightModeChar
+	case "word":
+		p.highlight = HighlightModeWord
+	case "line":
+		p.highlight = HighlightModeLine
+	}
+
+	return p
+}
+
+func (p *Pane) exec() {
+	var c *exec.Cmd
+	if p.cfg.Exec {
+		c = exec.Command(p.cmdArgs[0], p.cmdArgs[1:]...)
+	} else {
+		c = exec.Command("sh", "-c", strings.Join(p.cmdArgs, " "))
+	}
+
+	var buf bytes.Buffer
+	w := newANSIWriter(&buf, p.cfg.Ansi)
+	c.Stdout = w
+	c.Stderr = w
+	err := c.Run()
+
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			fmt.Fprintln(&buf, err.Error())
+		}
+	}
+
+	title := p.name
+	if p.isSuspended() {
+		title = fmt.Sprintf("%s [paused]", p.name)
+	}
+	p.content.SetTitle(title)
+	style := highlightStyle{
+		changed: p.cfg.ColorStyle,
+		added:   p.cfg.DiffAddedStyle,
+		removed: p.cfg.DiffRemovedStyle,
+		ansi:    p.cfg.Ansi,
+	}
+
+	p.mu.Lock()
+	text := renderHighlight(&p.cache, &p.tokens, p.highlight, style, buf.String())
+	p.mu.Unlock()
+
+	p.content.SetText(text)
+}
+
+// isSuspended reports whether the pane's ticks are currently paused. It is
+// safe to call from either the dashboard's UI goroutine or this pane's tick
+// goroutine.
+func (p *Pane) isSuspended() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.suspend
+}
+
+// toggleSuspend flips the pane's paused state and returns the new value.
+func (p *Pane) toggleSuspend() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.suspend = !p.suspend
+	return p.suspend
+}
+
+// cycleHighlight advances the pane's highlight mode by one, wrapping back
+// to HighlightModeOff after the last mode.
+func (p *Pane) cycleHighlight() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.highlight = (p.highlight + 1) % numHighlightMode
+}
+
+func (p *Pane) tick() {
+	t := time.NewTicker(time.Duration(p.cfg.Interval*1000) * time.Millisecond)
+	defer t.Stop()
+
+	p.exec()
+	for range t.C {
+		if !p.isSuspended() {
+			p.exec()
+		}
+	}
+}
+
+// parseCommands splits each "name:cmd" --command entry into a pane name
+// and command argument list.
+func parseCommands(entries []string) ([]string, [][]string) {
+	names := make([]string, len(entries))
+	cmds := make([][]string, len(entries))
+
+	for i, entry := range entries {
+		name, cmd := entry, entry
+		if idx := strings.Index(entry, ":"); idx >= 0 {
+			name, cmd = entry[:idx], entry[idx+1:]
+		}
+
+		names[i] = name
+		cmds[i] = strings.Fields(cmd)
+	}
+
+	return names, cmds
+}
+
+// firstEmptyCommand reports the first -C/--command entry (if any) whose
+// "name:cmd" part is empty once split and field-parsed the same way
+// parseCommands does, e.g. "name:" or "name: ". Under --exec such an entry
+// would make Pane.exec index into an empty cmdArgs and panic on its first
+// tick.
+func firstEmptyCommand(entries []string) (string, bool) {
+	for _, entry := range entries {
+		cmd := entry
+		if idx := strings.Index(entry, ":"); idx >= 0 {
+			cmd = entry[idx+1:]
+		}
+
+		if len(strings.Fields(cmd)) == 0 {
+			return entry, true
+		}
+	}
+
+	return "", false
+}
+
+// buildLayout arranges panes into a tview.Flex tree: a single row, a
+// single column, or a roughly square grid of both.
+func buildLayout(panes []*Pane, mode string) *tview.Flex {
+	switch mode {
+	case "rows":
+		f := tview.NewFlex().SetDirection(tview.FlexRow)
+		for _, p := range panes {
+			f.AddItem(p.content, 0, 1, false)
+		}
+		return f
+	case "cols":
+		f := tview.NewFlex().SetDirection(tview.FlexColumn)
+		for _, p := range panes {
+			f.AddItem(p.content, 0, 1, false)
+		}
+		return f
+	default:
+		cols := int(math.Ceil(math.Sqrt(float64(len(panes)))))
+		rows := int(math.Ceil(float64(len(panes)) / float64(cols)))
+
+		grid := tview.NewFlex().SetDirection(tview.FlexRow)
+		for r := 0; r < rows; r++ {
+			row := tview.NewFlex().SetDirection(tview.FlexColumn)
+			for c := 0; c < cols; c++ {
+				i := r*cols + c
+				if i >= len(panes) {
+					break
+				}
+				row.AddItem(panes[i].content, 0, 1, false)
+			}
+			grid.AddItem(row, 0, 1, false)
+		}
+		return grid
+	}
+}
+
+// runDashboard lays out one pane per --command entry and runs each on its
+// own tick goroutine. Tab/Shift-Tab move focus between panes; d and p
+// toggle highlighting and pausing on the focused pane only.
+func runDashboard(cfg config) {
+	names, cmds := parseCommands(cfg.Commands)
+
+	panes := make([]*Pane, len(names))
+	for i := range names {
+		panes[i] = NewPane(names[i], cmds[i], cfg)
+	}
+
+	ui := tview.NewApplication()
+
+	focus := 0
+	focusPane := func(i int) {
+		focus = (i + len(panes)) % len(panes)
+		ui.SetFocus(panes[focus].content)
+	}
+
+	for i, p := range panes {
+		p := p
+		i := i
+		p.content.SetChangedFunc(func() { ui.Draw() })
+		p.content.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			switch event.Key() {
+			case tcell.KeyTab:
+				focusPane(i + 1)
+				return nil
+			case tcell.KeyBacktab:
+				focusPane(i - 1)
+				return nil
+			}
+
+			switch event.Rune() {
+			case 'd':
+				p.cycleHighlight()
+			case 'p':
+				p.toggleSuspend()
+			case 'q':
+				ui.Stop()
+				os.Exit(0)
+			}
+			return event
+		})
+
+		go p.tick()
+	}
+
+	ui.SetRoot(buildLayout(panes, cfg.Layout), true)
+	focusPane(0)
+
+	if err := ui.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
 func main() {
 	var cfg config
 	cfg.Version = func() {
@@ -321,9 +1379,15 @@ func main() {
 	args, err := parser.Parse()
 	if err != nil {
 		os.Exit(1)
-	} else if len(args) == 0 {
+	} else if len(args) == 0 && len(cfg.Commands) == 0 {
 		parser.WriteHelp(os.Stderr)
 		os.Exit(1)
+	} else if cfg.JSON && len(cfg.Commands) > 0 {
+		fmt.Fprintln(os.Stderr, "gwatch: --json does not support -C/--command panes")
+		os.Exit(1)
+	} else if entry, ok := firstEmptyCommand(cfg.Commands); ok {
+		fmt.Fprintf(os.Stderr, "gwatch: -C/--command %q has no command\n", entry)
+		os.Exit(1)
 	}
 
 	if cfg.Interval < MinInterval {
@@ -334,6 +1398,20 @@ func main() {
 		cfg.ColorStyle = DefaultStyle
 	}
 
+	if cfg.HistorySize < 1 {
+		cfg.HistorySize = 1
+	}
+
+	if cfg.JSON {
+		runJSON(cfg, args)
+		return
+	}
+
+	if len(cfg.Commands) > 0 {
+		runDashboard(cfg)
+		return
+	}
+
 	app := NewApp(cfg)
 	app.Start(args)
 }